@@ -0,0 +1,170 @@
+package atproto
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/sirupsen/logrus"
+)
+
+const putRecordURL = "https://shareframe.social/xrpc/com.atproto.repo.putRecord"
+
+const (
+	maxRetries     = 4
+	baseBackoff    = 200 * time.Millisecond
+	requestTimeout = 5 * time.Second
+	maxIdlePerHost = 20
+)
+
+// Client is the interface HandleRequest depends on, so tests can substitute a
+// fake PDS without making real network calls.
+type Client interface {
+	PutRecord(ctx context.Context, repo, rkey string, record interface{}, bearerToken string, validate bool) error
+}
+
+var _ Client = (*PDSClient)(nil)
+
+// PDSClient wraps outbound com.atproto.repo.putRecord calls with HTTP
+// Signatures (draft-cavage) keyed off a KMS-backed service key, so the PDS
+// can attribute requests to this service independently of whichever user's
+// bearer token rides along, plus retry, timeouts and connection reuse tuned
+// for Lambda.
+type PDSClient struct {
+	httpClient *http.Client
+	signer     *kmsSigner
+}
+
+func NewClient() (*PDSClient, error) {
+	keyID := os.Getenv("ATPROTO_SERVICE_KMS_KEY_ID")
+	if keyID == "" {
+		return nil, fmt.Errorf("ATPROTO_SERVICE_KMS_KEY_ID is not set")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
+	}
+
+	return &PDSClient{
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: maxIdlePerHost,
+			},
+		},
+		signer: &kmsSigner{client: kms.NewFromConfig(awsCfg), keyID: keyID},
+	}, nil
+}
+
+// PutRecord writes record to repo/social.shareframe.profile/rkey, retrying
+// on 429/5xx with exponential backoff and jitter.
+func (c *PDSClient) PutRecord(ctx context.Context, repo, rkey string, record interface{}, bearerToken string, validate bool) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"repo":       repo,
+		"collection": "social.shareframe.profile",
+		"rkey":       rkey,
+		"validate":   validate,
+		"record":     record,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile update request: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithJitter(ctx, attempt); err != nil {
+				return err
+			}
+		}
+
+		statusCode, err := c.doPutRecord(ctx, body, bearerToken)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if !isRetryableStatus(statusCode) {
+			return lastErr
+		}
+
+		logrus.WithError(err).WithField("attempt", attempt).Warn("Retrying AT Protocol putRecord after transient failure")
+	}
+
+	return fmt.Errorf("putRecord failed after %d attempts: %w", maxRetries+1, lastErr)
+}
+
+// doPutRecord performs a single attempt, returning the response status code
+// (0 if the request never got a response) alongside any error.
+func (c *PDSClient) doPutRecord(ctx context.Context, body []byte, bearerToken string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, putRecordURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Digest", digestHeader(body))
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	if err := c.signer.Sign(ctx, req); err != nil {
+		return 0, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send profile update request to AT Protocol: %w", err)
+	}
+	defer drain(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("failed to update profile: %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// isRetryableStatus reports whether a putRecord attempt that got statusCode
+// is worth retrying. statusCode is 0 when the request never got a response
+// at all (a transport-level failure, or a failure to build or sign the
+// request), which is retried just like a 429 or 5xx.
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func digestHeader(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// drain reads the response body to completion before closing it, so
+// Lambda's HTTP/2 transport can actually reuse the underlying connection.
+func drain(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, body)
+	_ = body.Close()
+}
+
+func sleepWithJitter(ctx context.Context, attempt int) error {
+	backoff := baseBackoff * time.Duration(1<<uint(attempt-1))
+	jitter := time.Duration(rand.Int63n(int64(backoff)))
+
+	select {
+	case <-time.After(backoff + jitter):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
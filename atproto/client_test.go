@@ -0,0 +1,30 @@
+package atproto
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryableStatus(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		expect     bool
+	}{
+		{"transport failure (no response)", 0, true},
+		{"too many requests", http.StatusTooManyRequests, true},
+		{"internal server error", http.StatusInternalServerError, true},
+		{"bad gateway", http.StatusBadGateway, true},
+		{"bad request", http.StatusBadRequest, false},
+		{"unauthorized", http.StatusUnauthorized, false},
+		{"not found", http.StatusNotFound, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, isRetryableStatus(tc.statusCode))
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package confirmation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	pendingUpdatesTable = "PendingProfileUpdates"
+	rateLimitTable      = "ProfileChangeRateLimits"
+
+	tokenTTL        = 15 * time.Minute
+	rateLimitWindow = 1 * time.Hour
+	rateLimitMax    = 5
+)
+
+// ErrRateLimited is returned when a DID has requested too many pending
+// profile changes within rateLimitWindow.
+var ErrRateLimited = errors.New("too many pending profile change requests for this DID")
+
+// ErrTokenNotFound is returned when a confirmation token is unknown, expired,
+// or has already been consumed.
+var ErrTokenNotFound = errors.New("confirmation token not found or already used")
+
+// PendingUpdate is the profile change awaiting email confirmation.
+type PendingUpdate struct {
+	Token   string             `dynamodbav:"Token"`
+	DID     string             `dynamodbav:"DID"`
+	Profile models.UserProfile `dynamodbav:"Profile"`
+	TTL     int64              `dynamodbav:"TTL"`
+}
+
+// DynamoDBAPI is the subset of the DynamoDB client this package depends on.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// Store manages pending profile changes awaiting email confirmation.
+type Store interface {
+	CreatePending(ctx context.Context, did string, profile models.UserProfile) (token string, err error)
+	GetPending(ctx context.Context, token string) (*PendingUpdate, error)
+	MarkApplied(ctx context.Context, token string) error
+}
+
+var _ Store = (*DynamoStore)(nil)
+
+type DynamoStore struct {
+	Client DynamoDBAPI
+}
+
+func NewDynamoStore() (*DynamoStore, error) {
+	awsCfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
+	}
+
+	return &DynamoStore{Client: dynamodb.NewFromConfig(awsCfg)}, nil
+}
+
+// CreatePending rate-limits the DID, then stores profile under a fresh
+// single-use token with a TTL, returning the token to embed in the
+// confirmation email.
+func (s *DynamoStore) CreatePending(ctx context.Context, did string, profile models.UserProfile) (string, error) {
+	if err := s.checkRateLimit(ctx, did); err != nil {
+		return "", err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+
+	item, err := attributevalue.MarshalMap(PendingUpdate{
+		Token:   token,
+		DID:     did,
+		Profile: profile,
+		TTL:     time.Now().Add(tokenTTL).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pending profile update: %w", err)
+	}
+
+	if _, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(pendingUpdatesTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(Token)"),
+	}); err != nil {
+		return "", fmt.Errorf("failed to store pending profile update: %w", err)
+	}
+
+	return token, nil
+}
+
+// GetPending looks up the pending update for token without consuming it, so
+// the caller can apply the change and only mark it consumed once that
+// actually succeeds; a failed apply leaves the token intact for retry.
+func (s *DynamoStore) GetPending(ctx context.Context, token string) (*PendingUpdate, error) {
+	out, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(pendingUpdatesTable),
+		Key:       map[string]types.AttributeValue{"Token": &types.AttributeValueMemberS{Value: token}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up confirmation token: %w", err)
+	}
+
+	if len(out.Item) == 0 {
+		return nil, ErrTokenNotFound
+	}
+
+	var pending PendingUpdate
+	if err := attributevalue.UnmarshalMap(out.Item, &pending); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pending profile update: %w", err)
+	}
+
+	if pending.TTL <= time.Now().Unix() {
+		return nil, ErrTokenNotFound
+	}
+
+	return &pending, nil
+}
+
+// MarkApplied deletes the pending update for token once its profile change
+// has been durably applied, so the token can't be replayed. Call this only
+// after the apply has succeeded.
+func (s *DynamoStore) MarkApplied(ctx context.Context, token string) error {
+	_, err := s.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(pendingUpdatesTable),
+		Key:                 map[string]types.AttributeValue{"Token": &types.AttributeValueMemberS{Value: token}},
+		ConditionExpression: aws.String("attribute_exists(Token)"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark confirmation token applied: %w", err)
+	}
+
+	return nil
+}
+
+// checkRateLimit increments the per-DID counter, rejecting the request once
+// rateLimitMax has been reached. The counter item's TTL is set to
+// rateLimitWindow after its first increment; the count itself is never reset
+// here, so it only starts over once DynamoDB's TTL process has deleted the
+// item, which can happen some time after the TTL has passed.
+func (s *DynamoStore) checkRateLimit(ctx context.Context, did string) error {
+	out, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(rateLimitTable),
+		Key:                 map[string]types.AttributeValue{"DID": &types.AttributeValueMemberS{Value: did}},
+		UpdateExpression:    aws.String("SET #count = if_not_exists(#count, :zero) + :one, #ttl = if_not_exists(#ttl, :ttl)"),
+		ConditionExpression: aws.String("attribute_not_exists(#count) OR #count < :max"),
+		ExpressionAttributeNames: map[string]string{
+			"#count": "Count",
+			"#ttl":   "TTL",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":zero": &types.AttributeValueMemberN{Value: "0"},
+			":one":  &types.AttributeValueMemberN{Value: "1"},
+			":max":  &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", rateLimitMax)},
+			":ttl":  &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", time.Now().Add(rateLimitWindow).Unix())},
+		},
+		ReturnValues: types.ReturnValueNone,
+	})
+	if err != nil {
+		var condFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &condFailed) {
+			return ErrRateLimited
+		}
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	_ = out
+
+	return nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,112 @@
+package confirmation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDynamoDBAPI struct {
+	mock.Mock
+}
+
+func (m *MockDynamoDBAPI) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*dynamodb.PutItemOutput)
+	return out, args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*dynamodb.GetItemOutput)
+	return out, args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*dynamodb.DeleteItemOutput)
+	return out, args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*dynamodb.UpdateItemOutput)
+	return out, args.Error(1)
+}
+
+func TestGetPending_NotFound(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{}, nil)
+
+	_, err := store.GetPending(context.Background(), "missing-token")
+
+	assert.True(t, errors.Is(err, ErrTokenNotFound))
+}
+
+func TestGetPending_DoesNotDelete(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{
+		Item: map[string]types.AttributeValue{
+			"Token": &types.AttributeValueMemberS{Value: "tok"},
+			"DID":   &types.AttributeValueMemberS{Value: "did:plc:abc"},
+			"Profile": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"nsid":        &types.AttributeValueMemberS{Value: "social.shareframe.profile"},
+				"displayName": &types.AttributeValueMemberS{Value: "Jane"},
+				"updatedAt":   &types.AttributeValueMemberS{Value: "2026-01-01T00:00:00Z"},
+			}},
+			"TTL": &types.AttributeValueMemberN{Value: "4102444800"},
+		},
+	}, nil)
+
+	pending, err := store.GetPending(context.Background(), "tok")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "did:plc:abc", pending.DID)
+	mockDynamoDB.AssertNotCalled(t, "DeleteItem", mock.Anything, mock.Anything)
+}
+
+func TestMarkApplied_TokenAlreadyGone(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("DeleteItem", mock.Anything, mock.Anything).
+		Return((*dynamodb.DeleteItemOutput)(nil), &types.ConditionalCheckFailedException{})
+
+	err := store.MarkApplied(context.Background(), "tok")
+
+	assert.Error(t, err)
+}
+
+func TestCheckRateLimit_ConditionalCheckFailed_ReturnsErrRateLimited(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("UpdateItem", mock.Anything, mock.Anything).
+		Return((*dynamodb.UpdateItemOutput)(nil), &types.ConditionalCheckFailedException{})
+
+	err := store.checkRateLimit(context.Background(), "did:plc:abc")
+
+	assert.True(t, errors.Is(err, ErrRateLimited))
+}
+
+func TestCheckRateLimit_OtherError_IsNotRateLimited(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("UpdateItem", mock.Anything, mock.Anything).
+		Return((*dynamodb.UpdateItemOutput)(nil), errors.New("ProvisionedThroughputExceededException"))
+
+	err := store.checkRateLimit(context.Background(), "did:plc:abc")
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrRateLimited))
+}
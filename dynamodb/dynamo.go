@@ -2,24 +2,37 @@ package dynamodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/ShareFrame/update-profile-service/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// ErrStaleWrite is returned when UpdateUserInDynamoDB's optimistic
+// concurrency check fails because the stored record's UpdatedAt has moved
+// on since the caller last observed it.
+var ErrStaleWrite = errors.New("stale write: profile was updated concurrently")
+
+// ErrUserNotFound is returned when the requested UserId has no item in the
+// Users table.
+var ErrUserNotFound = errors.New("user not found")
+
 type DynamoDBAPI interface {
 	UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 }
 
 type DynamoDBService interface {
-	UpdateUserInDynamoDB(ctx context.Context, userID string, profile models.UserProfile) error
+	UpdateUserInDynamoDB(ctx context.Context, userID string, profile models.UserProfile) (*models.UserProfile, error)
+	GetUserEmail(ctx context.Context, userID string) (string, error)
 }
 
 var _ DynamoDBService = (*DynamoClient)(nil)
@@ -43,61 +56,130 @@ func NewDynamoClient() (*DynamoClient, error) {
 
 const tableName = "Users"
 
-func (d *DynamoClient) UpdateUserInDynamoDB(ctx context.Context, userID string, profile models.UserProfile) error {
+// UpdateUserInDynamoDB applies profile's non-nil fields to the user's item.
+// It is optimistically concurrent: profile.UpdatedAt must match the
+// UpdatedAt currently stored (or the item must not exist yet), otherwise the
+// write is rejected with ErrStaleWrite. It returns the user's profile as it
+// stood immediately before the update (nil if the item didn't exist yet), so
+// callers can derive a commit event's PrevCID.
+func (d *DynamoClient) UpdateUserInDynamoDB(ctx context.Context, userID string, profile models.UserProfile) (*models.UserProfile, error) {
 	if userID == "" {
-		return fmt.Errorf("userID cannot be empty")
+		return nil, fmt.Errorf("userID cannot be empty")
 	}
 
-	updateExpression, exprValues := buildUpdateExpression(profile)
-
-	if len(exprValues) == 1 {
-		return fmt.Errorf("no valid fields provided to update")
+	expr, err := buildUpdateExpression(profile)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("Updating UserId: %s, UpdateExpression: %s", userID, updateExpression)
+	log.Printf("Updating UserId: %s, UpdateExpression: %s", userID, aws.ToString(expr.Update()))
 
-	_, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	out, err := d.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName:                 aws.String(tableName),
 		Key:                       map[string]types.AttributeValue{"UserId": &types.AttributeValueMemberS{Value: userID}},
-		UpdateExpression:          aws.String("SET " + updateExpression),
-		ExpressionAttributeValues: exprValues,
-		ReturnValues:              types.ReturnValueUpdatedNew,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              types.ReturnValueAllOld,
 	})
 
 	if err != nil {
+		var condFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &condFailed) {
+			return nil, ErrStaleWrite
+		}
+
 		log.Printf("DynamoDB UpdateItem error: %v", err)
-		return fmt.Errorf("failed to update user in DynamoDB: %w", err)
+		return nil, fmt.Errorf("failed to update user in DynamoDB: %w", err)
 	}
 
-	return nil
+	if len(out.Attributes) == 0 {
+		return nil, nil
+	}
+
+	var previous models.UserProfile
+	if err := attributevalue.UnmarshalMap(out.Attributes, &previous); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal previous user record: %w", err)
+	}
+
+	return &previous, nil
 }
 
+// GetUserEmail returns the email address of record for userID, read directly
+// from the Users table rather than trusted from caller input, so a
+// confirmation link can never be redirected to an attacker-controlled
+// address.
+func (d *DynamoClient) GetUserEmail(ctx context.Context, userID string) (string, error) {
+	out, err := d.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key:       map[string]types.AttributeValue{"UserId": &types.AttributeValueMemberS{Value: userID}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if len(out.Item) == 0 {
+		return "", ErrUserNotFound
+	}
+
+	var user struct {
+		Email string `dynamodbav:"Email"`
+	}
+	if err := attributevalue.UnmarshalMap(out.Item, &user); err != nil {
+		return "", fmt.Errorf("failed to unmarshal user record: %w", err)
+	}
 
-func buildUpdateExpression(profile models.UserProfile) (string, map[string]types.AttributeValue) {
-	fields := map[string]string{
-		"DisplayName":    profile.DisplayName,
-		"Bio":            profile.Bio,
-		"ProfilePicture": profile.ProfilePicture,
-		"ProfileBanner":  profile.ProfileBanner,
-		"Theme":          profile.Theme,
-		"PrimaryColor":   profile.PrimaryColor,
-		"SecondaryColor": profile.SecondaryColor,
+	if user.Email == "" {
+		return "", fmt.Errorf("user %s has no email of record", userID)
 	}
 
-	updateParts := []string{}
-	exprValues := map[string]types.AttributeValue{}
+	return user.Email, nil
+}
 
-	for field, value := range fields {
-		if value != "" {
-			updateParts = append(updateParts, field+" = :"+field)
-			exprValues[":"+field] = &types.AttributeValueMemberS{Value: value}
+// buildUpdateExpression turns profile into an UpdateItem expression. Per
+// models.UserProfile's pointer-field convention: a nil pointer means "leave
+// this field alone" and contributes no clause at all, a pointer to "" means
+// "clear this field" and is REMOVEd, and any other pointer is SET.
+// UpdatedAt is always advanced to now under a condition that the caller's
+// profile.UpdatedAt matches what's currently stored (or that no item exists
+// yet).
+func buildUpdateExpression(profile models.UserProfile) (expression.Expression, error) {
+	update := expression.Set(expression.Name("UpdatedAt"), expression.Value(time.Now().Format(time.RFC3339)))
+	hasField := false
+
+	setOrRemove := func(field string, value *string) {
+		switch {
+		case value == nil:
+			return
+		case *value == "":
+			update = update.Remove(expression.Name(field))
+			hasField = true
+		default:
+			update = update.Set(expression.Name(field), expression.Value(*value))
+			hasField = true
 		}
 	}
 
-	exprValues[":UpdatedAt"] = &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)}
-	updateParts = append(updateParts, "UpdatedAt = :UpdatedAt")
+	if profile.DisplayName != "" {
+		update = update.Set(expression.Name("DisplayName"), expression.Value(profile.DisplayName))
+		hasField = true
+	}
+	setOrRemove("Bio", profile.Bio)
+	setOrRemove("ProfilePicture", profile.ProfilePicture)
+	setOrRemove("ProfileBanner", profile.ProfileBanner)
+	setOrRemove("Theme", profile.Theme)
+	setOrRemove("PrimaryColor", profile.PrimaryColor)
+	setOrRemove("SecondaryColor", profile.SecondaryColor)
+
+	if !hasField {
+		return expression.Expression{}, fmt.Errorf("no valid fields provided to update")
+	}
 
-	updateExpr := strings.Join(updateParts, ", ")
+	condition := expression.Or(
+		expression.AttributeNotExists(expression.Name("UpdatedAt")),
+		expression.Name("UpdatedAt").Equal(expression.Value(profile.UpdatedAt)),
+	)
 
-	return updateExpr, exprValues
+	return expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
 }
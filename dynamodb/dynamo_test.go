@@ -2,12 +2,14 @@ package dynamodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 	"testing"
 
 	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -22,6 +24,30 @@ func (m *MockDynamoDBAPI) UpdateItem(ctx context.Context, input *dynamodb.Update
 	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
 }
 
+func (m *MockDynamoDBAPI) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*dynamodb.GetItemOutput)
+	return out, args.Error(1)
+}
+
+func strPtr(s string) *string { return &s }
+
+func hasExpressionNames(input *dynamodb.UpdateItemInput, names ...string) bool {
+	for _, expected := range names {
+		found := false
+		for _, name := range input.ExpressionAttributeNames {
+			if name == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func TestUpdateUserInDynamoDB(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -42,47 +68,51 @@ func TestUpdateUserInDynamoDB(t *testing.T) {
 			userID: "user123",
 			profile: models.UserProfile{
 				DisplayName:    "John Doe",
-				Bio:            "Test Bio",
-				ProfilePicture: "picture.jpg",
-				ProfileBanner:  "banner.jpg",
-				Theme:          "dark",
-				PrimaryColor:   "blue",
-				SecondaryColor: "red",
+				Bio:            strPtr("Test Bio"),
+				ProfilePicture: strPtr("picture.jpg"),
+				ProfileBanner:  strPtr("banner.jpg"),
+				Theme:          strPtr("dark"),
+				PrimaryColor:   strPtr("blue"),
+				SecondaryColor: strPtr("red"),
+			},
+			mockReturn:  nil,
+			expectError: false,
+			expectedSet: []string{
+				"DisplayName", "Bio", "ProfilePicture", "ProfileBanner", "Theme", "PrimaryColor", "SecondaryColor", "UpdatedAt",
+			},
+		},
+		{
+			name:   "Update with only some fields, others left untouched",
+			userID: "user456",
+			profile: models.UserProfile{
+				DisplayName: "Jane Doe",
+				Bio:         strPtr("Hello world"),
 			},
 			mockReturn:  nil,
 			expectError: false,
 			expectedSet: []string{
-				"DisplayName = :DisplayName",
-				"Bio = :Bio",
-				"ProfilePicture = :ProfilePicture",
-				"ProfileBanner = :ProfileBanner",
-				"Theme = :Theme",
-				"PrimaryColor = :PrimaryColor",
-				"SecondaryColor = :SecondaryColor",
-				"UpdatedAt = :UpdatedAt",
+				"DisplayName", "Bio", "UpdatedAt",
 			},
 		},
 		{
-			name:   "Update with only some fields",
+			name:   "Explicit empty-string pointer clears a field",
 			userID: "user456",
 			profile: models.UserProfile{
 				DisplayName: "Jane Doe",
-				Bio:         "Hello world",
+				Bio:         strPtr(""),
 			},
 			mockReturn:  nil,
 			expectError: false,
 			expectedSet: []string{
-				"DisplayName = :DisplayName",
-				"Bio = :Bio",
-				"UpdatedAt = :UpdatedAt",
+				"DisplayName", "Bio", "UpdatedAt",
 			},
 		},
 		{
-			name:   "Only UpdatedAt is updated when no fields are provided",
-			userID: "user789",
-			profile: models.UserProfile{},
+			name:        "Error when no fields are provided",
+			userID:      "user789",
+			profile:     models.UserProfile{},
 			mockReturn:  nil,
-			expectError: true, 
+			expectError: true,
 			expectedSet: []string{},
 		},
 		{
@@ -94,8 +124,7 @@ func TestUpdateUserInDynamoDB(t *testing.T) {
 			mockReturn:  fmt.Errorf("DynamoDB update error"),
 			expectError: true,
 			expectedSet: []string{
-				"DisplayName = :DisplayName",
-				"UpdatedAt = :UpdatedAt",
+				"DisplayName", "UpdatedAt",
 			},
 		},
 	}
@@ -104,22 +133,17 @@ func TestUpdateUserInDynamoDB(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockDynamoDB.ExpectedCalls = nil
 
-			_, exprValues := buildUpdateExpression(tc.profile)
+			_, buildErr := buildUpdateExpression(tc.profile)
 
-			if len(exprValues) == 1 {
+			if buildErr != nil {
 				mockDynamoDB.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("no valid fields provided to update"))
 			} else {
 				mockDynamoDB.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
-					for _, expected := range tc.expectedSet {
-						if !strings.Contains(*input.UpdateExpression, expected) {
-							return false
-						}
-					}
-					return true
+					return hasExpressionNames(input, tc.expectedSet...)
 				})).Return(&dynamodb.UpdateItemOutput{}, tc.mockReturn)
 			}
 
-			err := client.UpdateUserInDynamoDB(context.Background(), tc.userID, tc.profile)
+			_, err := client.UpdateUserInDynamoDB(context.Background(), tc.userID, tc.profile)
 
 			if tc.expectError {
 				assert.Error(t, err, "Expected an error but got nil")
@@ -132,66 +156,180 @@ func TestUpdateUserInDynamoDB(t *testing.T) {
 	}
 }
 
-
 func TestBuildUpdateExpression(t *testing.T) {
 	testCases := []struct {
 		name             string
 		profile          models.UserProfile
-		expectedSetParts []string
+		expectedSetNames []string
+		expectErr        bool
 	}{
 		{
 			name: "All fields populated",
 			profile: models.UserProfile{
 				DisplayName:    "John Doe",
-				Bio:            "Test Bio",
-				ProfilePicture: "picture.jpg",
-				ProfileBanner:  "banner.jpg",
-				Theme:          "dark",
-				PrimaryColor:   "blue",
-				SecondaryColor: "red",
-			},
-			expectedSetParts: []string{
-				"DisplayName = :DisplayName",
-				"Bio = :Bio",
-				"ProfilePicture = :ProfilePicture",
-				"ProfileBanner = :ProfileBanner",
-				"Theme = :Theme",
-				"PrimaryColor = :PrimaryColor",
-				"SecondaryColor = :SecondaryColor",
-				"UpdatedAt = :UpdatedAt",
+				Bio:            strPtr("Test Bio"),
+				ProfilePicture: strPtr("picture.jpg"),
+				ProfileBanner:  strPtr("banner.jpg"),
+				Theme:          strPtr("dark"),
+				PrimaryColor:   strPtr("blue"),
+				SecondaryColor: strPtr("red"),
 			},
+			expectedSetNames: []string{"DisplayName", "Bio", "ProfilePicture", "ProfileBanner", "Theme", "PrimaryColor", "SecondaryColor", "UpdatedAt"},
 		},
 		{
 			name: "Some fields populated",
 			profile: models.UserProfile{
 				DisplayName: "Jane Doe",
-				Bio:         "Hello world",
-			},
-			expectedSetParts: []string{
-				"DisplayName = :DisplayName",
-				"Bio = :Bio",
-				"UpdatedAt = :UpdatedAt",
+				Bio:         strPtr("Hello world"),
 			},
+			expectedSetNames: []string{"DisplayName", "Bio", "UpdatedAt"},
 		},
 		{
-			name: "No fields populated (should only update UpdatedAt)",
-			profile: models.UserProfile{},
-			expectedSetParts: []string{
-				"UpdatedAt = :UpdatedAt",
-			},
+			name:      "No fields populated returns an error",
+			profile:   models.UserProfile{},
+			expectErr: true,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			updateExpression, exprValues := buildUpdateExpression(tc.profile)
+			expr, err := buildUpdateExpression(tc.profile)
 
-			for _, part := range tc.expectedSetParts {
-				assert.Contains(t, updateExpression, part, "Update expression missing expected field")
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
 			}
 
-			assert.Contains(t, exprValues, ":UpdatedAt", "UpdatedAt should always be in the expression values")
+			assert.NoError(t, err)
+
+			for _, name := range tc.expectedSetNames {
+				found := false
+				for _, actual := range expr.Names() {
+					if actual == name {
+						found = true
+						break
+					}
+				}
+				assert.True(t, found, "update expression missing expected field %s", name)
+			}
+			assert.NotNil(t, expr.Condition())
 		})
 	}
 }
 
+func TestBuildUpdateExpression_NilVsEmptyPointer(t *testing.T) {
+	expr, err := buildUpdateExpression(models.UserProfile{
+		DisplayName: "Jane Doe",
+		Bio:         strPtr(""),
+		Theme:       strPtr("dark"),
+	})
+	assert.NoError(t, err)
+
+	names := make(map[string]bool)
+	for _, name := range expr.Names() {
+		names[name] = true
+	}
+
+	assert.True(t, names["Bio"], "an explicit pointer-to-empty-string field must still appear, as a REMOVE")
+	assert.True(t, names["Theme"], "a populated field must be SET")
+	assert.False(t, names["ProfilePicture"], "a nil (omitted) pointer field must contribute no clause at all")
+	assert.False(t, names["ProfileBanner"], "a nil (omitted) pointer field must contribute no clause at all")
+	assert.False(t, names["PrimaryColor"], "a nil (omitted) pointer field must contribute no clause at all")
+	assert.False(t, names["SecondaryColor"], "a nil (omitted) pointer field must contribute no clause at all")
+
+	assert.Contains(t, aws.ToString(expr.Update()), "REMOVE", "an explicit pointer-to-empty-string field must produce a REMOVE clause")
+}
+
+func TestGetUserEmail(t *testing.T) {
+	testCases := []struct {
+		name        string
+		getItemOut  *dynamodb.GetItemOutput
+		getItemErr  error
+		expectEmail string
+		expectErr   error
+	}{
+		{
+			name: "returns email of record",
+			getItemOut: &dynamodb.GetItemOutput{
+				Item: map[string]types.AttributeValue{
+					"UserId": &types.AttributeValueMemberS{Value: "user123"},
+					"Email":  &types.AttributeValueMemberS{Value: "user@example.com"},
+				},
+			},
+			expectEmail: "user@example.com",
+		},
+		{
+			name:       "no item found",
+			getItemOut: &dynamodb.GetItemOutput{},
+			expectErr:  ErrUserNotFound,
+		},
+		{
+			name:       "DynamoDB error",
+			getItemErr: fmt.Errorf("throttled"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDynamoDB := new(MockDynamoDBAPI)
+			mockDynamoDB.On("GetItem", mock.Anything, mock.Anything).Return(tc.getItemOut, tc.getItemErr)
+
+			client := &DynamoClient{Client: mockDynamoDB}
+			email, err := client.GetUserEmail(context.Background(), "user123")
+
+			if tc.expectErr != nil {
+				assert.True(t, errors.Is(err, tc.expectErr))
+			} else if tc.getItemErr != nil {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectEmail, email)
+			}
+		})
+	}
+}
+
+func TestUpdateUserInDynamoDB_StaleWrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDynamoDB := new(MockDynamoDBAPI)
+	client := &DynamoClient{Client: mockDynamoDB}
+
+	mockDynamoDB.On("UpdateItem", mock.Anything, mock.Anything).
+		Return((*dynamodb.UpdateItemOutput)(nil), &types.ConditionalCheckFailedException{})
+
+	_, err := client.UpdateUserInDynamoDB(context.Background(), "user123", models.UserProfile{DisplayName: "Jane Doe"})
+
+	assert.True(t, errors.Is(err, ErrStaleWrite))
+}
+
+func TestUpdateUserInDynamoDB_ReturnsPreviousProfile(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	client := &DynamoClient{Client: mockDynamoDB}
+
+	mockDynamoDB.On("UpdateItem", mock.Anything, mock.Anything).Return(&dynamodb.UpdateItemOutput{
+		Attributes: map[string]types.AttributeValue{
+			"DisplayName": &types.AttributeValueMemberS{Value: "Old Name"},
+			"UpdatedAt":   &types.AttributeValueMemberS{Value: "2026-01-01T00:00:00Z"},
+		},
+	}, nil)
+
+	previous, err := client.UpdateUserInDynamoDB(context.Background(), "user123", models.UserProfile{DisplayName: "New Name"})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, previous)
+	assert.Equal(t, "Old Name", previous.DisplayName)
+}
+
+func TestUpdateUserInDynamoDB_NoPreviousItem(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	client := &DynamoClient{Client: mockDynamoDB}
+
+	mockDynamoDB.On("UpdateItem", mock.Anything, mock.Anything).Return(&dynamodb.UpdateItemOutput{}, nil)
+
+	previous, err := client.UpdateUserInDynamoDB(context.Background(), "user123", models.UserProfile{DisplayName: "New Name"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, previous)
+}
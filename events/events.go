@@ -0,0 +1,87 @@
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+const collection = "social.shareframe.profile"
+
+// CommitEvent is a firehose-compatible representation of a single repo
+// commit, published so downstream services (search indexer, feed
+// generators, notification workers) can subscribe instead of polling
+// DynamoDB Streams.
+type CommitEvent struct {
+	DID        string `json:"did"`
+	Collection string `json:"collection"`
+	Rkey       string `json:"rkey"`
+	CID        string `json:"cid"`
+	PrevCID    string `json:"prevCid,omitempty"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// Publisher publishes commit events. Publish returns an error on failure;
+// the handler logs it rather than failing the user-facing response.
+type Publisher interface {
+	Publish(ctx context.Context, event CommitEvent) error
+}
+
+var _ Publisher = (*SNSPublisher)(nil)
+
+type SNSPublisher struct {
+	Client   *sns.Client
+	TopicARN string
+}
+
+func NewSNSPublisher() (*SNSPublisher, error) {
+	topicARN := os.Getenv("PROFILE_COMMIT_TOPIC_ARN")
+	if topicARN == "" {
+		return nil, fmt.Errorf("PROFILE_COMMIT_TOPIC_ARN is not set")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
+	}
+
+	return &SNSPublisher{
+		Client:   sns.NewFromConfig(awsCfg),
+		TopicARN: topicARN,
+	}, nil
+}
+
+func (p *SNSPublisher) Publish(ctx context.Context, event CommitEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal commit event: %w", err)
+	}
+
+	_, err = p.Client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(p.TopicARN),
+		Message:  aws.String(string(body)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish commit event: %w", err)
+	}
+
+	return nil
+}
+
+// CommitCID derives a content-addressed identifier for the record so
+// subscribers can detect duplicate or out-of-order deliveries. It is not a
+// full AT Protocol CID (no CBOR/multihash encoding), only a stable
+// placeholder until the repo commit path produces a real one.
+func CommitCID(profile models.UserProfile) string {
+	body, _ := json.Marshal(profile)
+	sum := sha256.Sum256(body)
+	return "bafy" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitCID_IsDeterministic(t *testing.T) {
+	profile := models.UserProfile{DisplayName: "Jane Doe"}
+
+	assert.Equal(t, CommitCID(profile), CommitCID(profile))
+}
+
+func TestCommitCID_DiffersForDifferentProfiles(t *testing.T) {
+	a := models.UserProfile{DisplayName: "Jane Doe"}
+	b := models.UserProfile{DisplayName: "John Doe"}
+
+	assert.NotEqual(t, CommitCID(a), CommitCID(b))
+}
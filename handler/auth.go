@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	plcDirectoryURL  = "https://plc.directory/%s"
+	didDocumentTTL   = 10 * time.Minute
+	serviceJWTIssuer = "" // iss is asserted to equal the request DID, not a fixed value
+	expectedAudience = "did:web:shareframe.social"
+)
+
+// VerificationMethod mirrors the subset of a DID document's verificationMethod
+// entries we need to validate an AT Protocol service JWT.
+type VerificationMethod struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	PublicKeyMultibase string `json:"publicKeyMultibase"`
+}
+
+// DIDDocument is the subset of https://www.w3.org/TR/did-core/ we rely on.
+type DIDDocument struct {
+	ID                 string               `json:"id"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod"`
+}
+
+type cachedDIDDocument struct {
+	doc       *DIDDocument
+	expiresAt time.Time
+}
+
+// DIDResolver resolves did:plc and did:web identifiers to DID documents,
+// caching results for didDocumentTTL to avoid hammering the PLC directory
+// on every Lambda invocation.
+type DIDResolver struct {
+	httpClient *http.Client
+	mu         sync.RWMutex
+	cache      map[string]cachedDIDDocument
+}
+
+func NewDIDResolver() *DIDResolver {
+	return &DIDResolver{
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		cache:      make(map[string]cachedDIDDocument),
+	}
+}
+
+func (r *DIDResolver) Resolve(ctx context.Context, did string) (*DIDDocument, error) {
+	if cached, ok := r.fromCache(did); ok {
+		return cached, nil
+	}
+
+	var docURL string
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		docURL = fmt.Sprintf(plcDirectoryURL, did)
+	case strings.HasPrefix(did, "did:web:"):
+		docURL = didWebDocumentURL(did)
+	default:
+		return nil, fmt.Errorf("unsupported DID method: %s", did)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DID document request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve DID document for %s: %w", did, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DID document lookup failed for %s: %s", did, resp.Status)
+	}
+
+	var doc DIDDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode DID document for %s: %w", did, err)
+	}
+
+	r.store(did, &doc)
+
+	return &doc, nil
+}
+
+func (r *DIDResolver) fromCache(did string) (*DIDDocument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.cache[did]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.doc, true
+}
+
+func (r *DIDResolver) store(did string, doc *DIDDocument) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cache[did] = cachedDIDDocument{doc: doc, expiresAt: time.Now().Add(didDocumentTTL)}
+}
+
+func didWebDocumentURL(did string) string {
+	id := strings.TrimPrefix(did, "did:web:")
+	parts := strings.Split(id, ":")
+	for i, part := range parts {
+		parts[i] = part
+	}
+	host := parts[0]
+	path := "/.well-known/did.json"
+	if len(parts) > 1 {
+		path = "/" + strings.Join(parts[1:], "/") + "/did.json"
+	}
+	return "https://" + host + path
+}
+
+// verifyServiceJWT validates authToken as an AT Protocol service JWT issued by
+// did, checking signature, iss, aud, exp and nbf against the caller's DID
+// document. It returns an error describing why the token is unauthorized.
+func verifyServiceJWT(ctx context.Context, resolver *DIDResolver, did, authToken string) error {
+	if authToken == "" {
+		return fmt.Errorf("missing auth token")
+	}
+
+	doc, err := resolver.Resolve(ctx, did)
+	if err != nil {
+		return fmt.Errorf("failed to resolve signer DID: %w", err)
+	}
+
+	token, err := jwt.Parse(authToken, func(t *jwt.Token) (interface{}, error) {
+		return publicKeyForToken(doc, t)
+	}, jwt.WithValidMethods([]string{"ES256", sigES256K}))
+	if err != nil || !token.Valid {
+		return fmt.Errorf("auth token signature verification failed: %w", err)
+	}
+
+	// exp/nbf/iat are already checked by jwt.Parse's default validator.
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return fmt.Errorf("auth token has malformed claims")
+	}
+
+	iss, _ := claims["iss"].(string)
+	if iss != did {
+		return fmt.Errorf("auth token iss %q does not match requested DID %q", iss, did)
+	}
+
+	if aud, ok := claims["aud"].(string); !ok || aud != expectedAudience {
+		return fmt.Errorf("auth token aud does not match this service")
+	}
+
+	logrus.WithField("did", did).Info("Auth token verified successfully")
+
+	return nil
+}
+
+// publicKeyForToken finds the verification method referenced by the token's
+// kid (or the first usable one, if unset) and returns its public key.
+func publicKeyForToken(doc *DIDDocument, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	for _, vm := range doc.VerificationMethod {
+		if kid != "" && vm.ID != kid {
+			continue
+		}
+		key, err := parseMultibasePublicKey(vm)
+		if err != nil && kid == "" {
+			continue
+		}
+		return key, err
+	}
+
+	return nil, fmt.Errorf("no matching verificationMethod found for kid %q", kid)
+}
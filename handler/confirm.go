@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ShareFrame/update-profile-service/atproto"
+	"github.com/ShareFrame/update-profile-service/confirmation"
+	"github.com/ShareFrame/update-profile-service/dynamodb"
+	"github.com/ShareFrame/update-profile-service/idempotency"
+	"github.com/ShareFrame/update-profile-service/mail"
+	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+const confirmationBaseURL = "https://shareframe.social/profile/confirm?token=%s"
+
+// RequestProfileChangeConfirmation is the Lambda entry point for high-risk
+// profile edits (DisplayName, ProfilePicture). It authenticates the caller,
+// stashes the requested profile under a short-lived token, and emails the
+// account of record a confirmation link instead of applying the change
+// immediately.
+func RequestProfileChangeConfirmation(ctx context.Context, request models.ProfileChangeConfirmationRequest) (models.ProfileChangeConfirmationResponse, error) {
+	logrus.WithField("did", request.DID).Info("Processing profile change confirmation request")
+
+	if err := verifyServiceJWT(ctx, didResolver, request.DID, request.AuthToken); err != nil {
+		logrus.WithError(err).Warn("Rejected unauthorized profile change confirmation request")
+		return models.ProfileChangeConfirmationResponse{Message: "Unauthorized", Success: false}, nil
+	}
+
+	if err := validateProfile(request.Profile); err != nil {
+		logrus.WithError(err).Warn("Profile validation failed")
+		return models.ProfileChangeConfirmationResponse{Message: "Profile validation failed", Success: false}, nil
+	}
+
+	dynamoClient, err := dynamodb.NewDynamoClient()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize DynamoDB client")
+		return models.ProfileChangeConfirmationResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	email, err := dynamoClient.GetUserEmail(ctx, request.DID)
+	if errors.Is(err, dynamodb.ErrUserNotFound) {
+		logrus.WithField("did", request.DID).Warn("Rejected profile change confirmation request: unknown DID")
+		return models.ProfileChangeConfirmationResponse{Message: "Unauthorized", Success: false}, nil
+	} else if err != nil {
+		logrus.WithError(err).Error("Failed to look up email of record")
+		return models.ProfileChangeConfirmationResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	store, err := confirmation.NewDynamoStore()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize confirmation store")
+		return models.ProfileChangeConfirmationResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	token, err := store.CreatePending(ctx, request.DID, request.Profile)
+	if errors.Is(err, confirmation.ErrRateLimited) {
+		logrus.WithField("did", request.DID).Warn("Rejected profile change confirmation request: rate limited")
+		return models.ProfileChangeConfirmationResponse{Message: "Too many pending profile change requests, try again later", Success: false}, nil
+	} else if err != nil {
+		logrus.WithError(err).Error("Failed to store pending profile update")
+		return models.ProfileChangeConfirmationResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	sender, err := mail.NewSESSender()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize email sender")
+		return models.ProfileChangeConfirmationResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	confirmationURL := fmt.Sprintf(confirmationBaseURL, token)
+	if err := sender.SendConfirmationEmail(ctx, email, confirmationURL); err != nil {
+		logrus.WithError(err).Error("Failed to send confirmation email")
+		return models.ProfileChangeConfirmationResponse{Message: "Failed to send confirmation email", Success: false}, err
+	}
+
+	logrus.WithField("did", request.DID).Info("Sent profile change confirmation email")
+
+	return models.ProfileChangeConfirmationResponse{
+		Message: "Confirmation email sent",
+		Success: true,
+	}, nil
+}
+
+// ConfirmProfileChange is the Lambda entry point the confirmation link in the
+// email resolves to. It looks up the pending profile for the token, applies
+// it (publishing a commit event on success, same as the normal update path),
+// and only then marks the token applied, so a failure partway through leaves
+// the token intact for the caller to retry rather than losing the confirmed
+// change.
+func ConfirmProfileChange(ctx context.Context, request models.ConfirmProfileChangeRequest) (models.UpdateProfileResponse, error) {
+	store, err := confirmation.NewDynamoStore()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize confirmation store")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	pending, err := store.GetPending(ctx, request.Token)
+	if errors.Is(err, confirmation.ErrTokenNotFound) {
+		logrus.Warn("Rejected profile change confirmation: token not found or already used")
+		return models.UpdateProfileResponse{Message: "Confirmation link is invalid or has expired", Success: false}, nil
+	} else if err != nil {
+		logrus.WithError(err).Error("Failed to look up confirmation token")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	logrus.WithField("did", pending.DID).Info("Confirmed pending profile change")
+
+	dynamoClient, err := dynamodb.NewDynamoClient()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize DynamoDB client")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	atprotoClient, err := atproto.NewClient()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize AT Protocol client")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	rkey := idempotency.DeriveRKey(pending.DID)
+
+	if err := atprotoClient.PutRecord(ctx, pending.DID, rkey, pending.Profile, "", false); err != nil {
+		logrus.WithError(err).Error("Failed to update profile in AT Protocol")
+		return models.UpdateProfileResponse{Message: "Failed to update profile in AT Protocol", Success: false}, err
+	}
+
+	previousProfile, err := dynamoClient.UpdateUserInDynamoDB(ctx, pending.DID, pending.Profile)
+	if err != nil {
+		if errors.Is(err, dynamodb.ErrStaleWrite) {
+			logrus.WithField("did", pending.DID).Warn("Rejected confirmed profile change: conflicting concurrent write")
+			return models.UpdateProfileResponse{Message: "Conflict: profile was updated concurrently", Success: false}, nil
+		}
+		logrus.WithError(err).Error("Failed to update profile in DynamoDB")
+		return models.UpdateProfileResponse{Message: "Failed to update profile in database", Success: false}, err
+	}
+
+	publishCommitEvent(ctx, newRealPublisher, pending.DID, rkey, pending.Profile, previousProfile)
+
+	if err := store.MarkApplied(ctx, request.Token); err != nil {
+		logrus.WithError(err).WithField("did", pending.DID).Warn("Failed to mark confirmation token applied; it may be replayed, which is harmless since the update is already durable")
+	}
+
+	logrus.WithField("did", pending.DID).Info("Applied confirmed profile change")
+
+	return models.UpdateProfileResponse{
+		Message: "Profile updated successfully",
+		Success: true,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// sigES256K is the alg value AT Protocol uses for secp256k1-keyed DIDs.
+// golang-jwt only registers ES256/ES384/ES512 out of the box, so we
+// register a verify-only SigningMethod for it here.
+const sigES256K = "ES256K"
+
+func init() {
+	jwt.RegisterSigningMethod(sigES256K, func() jwt.SigningMethod {
+		return signingMethodES256K{}
+	})
+}
+
+type signingMethodES256K struct{}
+
+func (signingMethodES256K) Alg() string { return sigES256K }
+
+func (signingMethodES256K) Verify(signingString string, sig []byte, key interface{}) error {
+	pub, ok := key.(*secp256k1.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	if len(sig) != 64 {
+		return fmt.Errorf("ES256K: invalid signature length %d", len(sig))
+	}
+
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(sig[:32]) {
+		return fmt.Errorf("ES256K: invalid signature")
+	}
+	if s.SetByteSlice(sig[32:]) {
+		return fmt.Errorf("ES256K: invalid signature")
+	}
+
+	hash := sha256.Sum256([]byte(signingString))
+	if !ecdsa.NewSignature(&r, &s).Verify(hash[:], pub) {
+		return jwt.ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// Sign is unimplemented: this service only verifies caller-supplied tokens,
+// it never issues its own ES256K-signed ones.
+func (signingMethodES256K) Sign(signingString string, key interface{}) ([]byte, error) {
+	return nil, jwt.ErrInvalidKeyType
+}
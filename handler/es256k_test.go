@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func rawSignature(t *testing.T, priv *secp256k1.PrivateKey, signingString string) []byte {
+	t.Helper()
+
+	hash := sha256.Sum256([]byte(signingString))
+	sig := ecdsa.Sign(priv, hash[:])
+
+	r := sig.R()
+	s := sig.S()
+
+	raw := make([]byte, 64)
+	rBytes := r.Bytes()
+	sBytes := s.Bytes()
+	copy(raw[:32], rBytes[:])
+	copy(raw[32:], sBytes[:])
+
+	return raw
+}
+
+func TestSigningMethodES256K_RegisteredAndVerifies(t *testing.T) {
+	method := jwt.GetSigningMethod(sigES256K)
+	if assert.NotNil(t, method) {
+		assert.Equal(t, sigES256K, method.Alg())
+	}
+
+	priv, err := secp256k1.GeneratePrivateKey()
+	assert.NoError(t, err)
+
+	const signingString = "header.payload"
+	sig := rawSignature(t, priv, signingString)
+
+	assert.NoError(t, method.Verify(signingString, sig, priv.PubKey()))
+}
+
+func TestSigningMethodES256K_RejectsWrongKeyType(t *testing.T) {
+	method := signingMethodES256K{}
+
+	err := method.Verify("header.payload", make([]byte, 64), "not a secp256k1 key")
+
+	assert.ErrorIs(t, err, jwt.ErrInvalidKeyType)
+}
+
+func TestSigningMethodES256K_RejectsBadSignatureLength(t *testing.T) {
+	method := signingMethodES256K{}
+
+	priv, err := secp256k1.GeneratePrivateKey()
+	assert.NoError(t, err)
+
+	err = method.Verify("header.payload", []byte{1, 2, 3}, priv.PubKey())
+
+	assert.Error(t, err)
+}
+
+func TestSigningMethodES256K_RejectsTamperedSignature(t *testing.T) {
+	method := signingMethodES256K{}
+
+	priv, err := secp256k1.GeneratePrivateKey()
+	assert.NoError(t, err)
+
+	sig := rawSignature(t, priv, "header.payload")
+	sig[0] ^= 0xFF
+
+	err = method.Verify("header.payload", sig, priv.PubKey())
+
+	assert.ErrorIs(t, err, jwt.ErrSignatureInvalid)
+}
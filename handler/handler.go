@@ -1,25 +1,25 @@
 package handler
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/ShareFrame/update-profile-service/atproto"
 	"github.com/ShareFrame/update-profile-service/dynamodb"
+	"github.com/ShareFrame/update-profile-service/events"
+	"github.com/ShareFrame/update-profile-service/idempotency"
 	"github.com/ShareFrame/update-profile-service/models"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 var (
 	hexColorRegex = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}){1,2}$`)
 	urlRegex      = regexp.MustCompile(`^(https?://)?([a-zA-Z0-9.-]+)(:[0-9]+)?(/.*)?$`)
+
+	didResolver = NewDIDResolver()
 )
 
 func validateProfile(profile models.UserProfile) error {
@@ -32,17 +32,18 @@ func validateProfile(profile models.UserProfile) error {
 	}
 
 	validations := map[string]func() bool{
-		"bio must be 256 characters or fewer": func() bool { return len(profile.Bio) > 256 },
-		"invalid profilePicture URL":          func() bool { return profile.ProfilePicture != "" && !isValidURL(profile.ProfilePicture) },
-		"invalid profileBanner URL":           func() bool { return profile.ProfileBanner != "" && !isValidURL(profile.ProfileBanner) },
+		"bio must be 256 characters or fewer": func() bool { return len(deref(profile.Bio)) > 256 },
+		"invalid profilePicture URL":          func() bool { return notEmpty(profile.ProfilePicture) && !isValidURL(*profile.ProfilePicture) },
+		"invalid profileBanner URL":           func() bool { return notEmpty(profile.ProfileBanner) && !isValidURL(*profile.ProfileBanner) },
 		"theme must be 'light', 'dark', or 'custom'": func() bool {
-			return profile.Theme != "" && profile.Theme != "light" && profile.Theme != "dark" && profile.Theme != "custom"
+			theme := deref(profile.Theme)
+			return theme != "" && theme != "light" && theme != "dark" && theme != "custom"
 		},
 		"primaryColor must be a valid hex code (e.g., #RRGGBB or #RGB)": func() bool {
-			return profile.PrimaryColor != "" && !isValidHexColor(profile.PrimaryColor)
+			return notEmpty(profile.PrimaryColor) && !isValidHexColor(*profile.PrimaryColor)
 		},
 		"secondaryColor must be a valid hex code (e.g., #RRGGBB or #RGB)": func() bool {
-			return profile.SecondaryColor != "" && !isValidHexColor(profile.SecondaryColor)
+			return notEmpty(profile.SecondaryColor) && !isValidHexColor(*profile.SecondaryColor)
 		},
 	}
 
@@ -79,85 +80,165 @@ func isValidHexColor(color string) bool {
 	return hexColorRegex.MatchString(color)
 }
 
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func notEmpty(s *string) bool {
+	return s != nil && *s != ""
+}
+
+// handlerDeps holds HandleRequest's external dependencies behind factory
+// functions, mirroring the lazy, per-invocation construction the real
+// NewXxx calls already use, so tests can substitute fakes without needing
+// live AWS credentials or network access.
+type handlerDeps struct {
+	newDynamoClient     func() (dynamodb.DynamoDBService, error)
+	newIdempotencyStore func() (idempotency.Store, error)
+	newAtprotoClient    func() (atproto.Client, error)
+	newPublisher        func() (events.Publisher, error)
+	verifyJWT           func(ctx context.Context, did, authToken string) error
+}
+
+func newRealDynamoClient() (dynamodb.DynamoDBService, error) { return dynamodb.NewDynamoClient() }
+func newRealIdempotencyStore() (idempotency.Store, error)    { return idempotency.NewDynamoStore() }
+func newRealAtprotoClient() (atproto.Client, error)          { return atproto.NewClient() }
+func newRealPublisher() (events.Publisher, error)            { return events.NewSNSPublisher() }
+
+var defaultHandlerDeps = handlerDeps{
+	newDynamoClient:     newRealDynamoClient,
+	newIdempotencyStore: newRealIdempotencyStore,
+	newAtprotoClient:    newRealAtprotoClient,
+	newPublisher:        newRealPublisher,
+	verifyJWT: func(ctx context.Context, did, authToken string) error {
+		return verifyServiceJWT(ctx, didResolver, did, authToken)
+	},
+}
+
 func HandleRequest(ctx context.Context, request models.RequestPayload) (models.UpdateProfileResponse, error) {
-    logrus.WithField("did", request.DID).Info("Processing profile update request")
+	return handleRequest(ctx, request, defaultHandlerDeps)
+}
 
-    dynamoClient, err := dynamodb.NewDynamoClient()
-    if err != nil {
-        logrus.WithError(err).Error("Failed to initialize DynamoDB client")
-        return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
-    }
+func handleRequest(ctx context.Context, request models.RequestPayload, deps handlerDeps) (models.UpdateProfileResponse, error) {
+	logrus.WithField("did", request.DID).Info("Processing profile update request")
 
-    profile := request.Profile
+	dynamoClient, err := deps.newDynamoClient()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize DynamoDB client")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
 
-    if err := validateProfile(profile); err != nil {
-        logrus.WithError(err).Warn("Profile validation failed")
-        return models.UpdateProfileResponse{Message: "Profile validation failed", Success: false}, nil
-    }
+	if err := deps.verifyJWT(ctx, request.DID, request.AuthToken); err != nil {
+		logrus.WithError(err).Warn("Rejected unauthorized profile update request")
+		return models.UpdateProfileResponse{Message: "Unauthorized", Success: false}, nil
+	}
 
-    logrus.WithField("profile", profile).Info("Validated profile successfully")
+	profile := request.Profile
 
-    _, err = updateProfileInATProtocol(request.DID, profile, request.AuthToken, false)
-    if err != nil {
-        logrus.WithError(err).Error("Failed to update profile in AT Protocol")
-        return models.UpdateProfileResponse{Message: "Failed to update profile in AT Protocol", Success: false}, err
-    }
+	if err := validateProfile(profile); err != nil {
+		logrus.WithError(err).Warn("Profile validation failed")
+		return models.UpdateProfileResponse{Message: "Profile validation failed", Success: false}, nil
+	}
 
-    if err := dynamoClient.UpdateUserInDynamoDB(ctx, request.DID, profile); err != nil {
-        logrus.WithError(err).Error("Failed to update profile in DynamoDB")
-        return models.UpdateProfileResponse{Message: "Failed to update profile in database", Success: false}, err
-    }
+	logrus.WithField("profile", profile).Info("Validated profile successfully")
 
-    logrus.WithField("did", request.DID).Info("Updated profile in DynamoDB successfully")
-    logrus.Info("Profile update completed successfully")
+	idempotencyStore, err := deps.newIdempotencyStore()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initialize idempotency store")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
 
-    return models.UpdateProfileResponse{
-        Message: "Profile updated successfully",
-        Success: true,
-    }, nil
-}
+	payloadHash, err := idempotency.HashPayload(request.DID, profile)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to hash profile payload")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	cached, err := idempotencyStore.Lookup(ctx, request.IdempotencyKey, request.DID, payloadHash)
+	if errors.Is(err, idempotency.ErrPayloadMismatch) {
+		logrus.WithField("did", request.DID).Warn("Rejected profile update: idempotency key reused with a different payload")
+		return models.UpdateProfileResponse{Message: "Idempotency key already used for a different request", Success: false}, nil
+	} else if err != nil {
+		logrus.WithError(err).Error("Failed to check idempotency store")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	} else if cached != nil {
+		logrus.WithField("did", request.DID).Info("Returning cached response for repeated idempotency key")
+		return *cached, nil
+	}
 
-func updateProfileInATProtocol(repo string, profile models.UserProfile, bearerToken string, validate bool) (string, error) {
-	updateURL := "https://shareframe.social/xrpc/com.atproto.repo.putRecord"
-	rkey := uuid.New().String()
-
-	body, err := json.Marshal(map[string]interface{}{
-		"repo":       repo,
-		"collection": "social.shareframe.profile",
-		"rkey":       rkey,
-		"validate":   validate,
-		"record":     profile,
-	})
+	rkey := idempotency.DeriveRKey(request.DID)
+
+	atprotoClient, err := deps.newAtprotoClient()
 	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal profile update request")
-		return "", err
+		logrus.WithError(err).Error("Failed to initialize AT Protocol client")
+		return models.UpdateProfileResponse{Message: "Internal server error", Success: false}, err
+	}
+
+	if err := atprotoClient.PutRecord(ctx, request.DID, rkey, profile, request.AuthToken, false); err != nil {
+		logrus.WithError(err).Error("Failed to update profile in AT Protocol")
+		return models.UpdateProfileResponse{Message: "Failed to update profile in AT Protocol", Success: false}, err
 	}
 
-	req, err := http.NewRequest("POST", updateURL, bytes.NewBuffer(body))
+	previousProfile, err := dynamoClient.UpdateUserInDynamoDB(ctx, request.DID, profile)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create HTTP request")
-		return "", err
+		if errors.Is(err, dynamodb.ErrStaleWrite) {
+			logrus.WithField("did", request.DID).Warn("Rejected profile update: conflicting concurrent write")
+			return models.UpdateProfileResponse{Message: "Conflict: profile was updated concurrently", Success: false}, nil
+		}
+		logrus.WithError(err).Error("Failed to update profile in DynamoDB")
+		return models.UpdateProfileResponse{Message: "Failed to update profile in database", Success: false}, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	logrus.WithField("did", request.DID).Info("Updated profile in DynamoDB successfully")
+
+	publishCommitEvent(ctx, deps.newPublisher, request.DID, rkey, profile, previousProfile)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	logrus.Info("Profile update completed successfully")
+
+	response := models.UpdateProfileResponse{
+		Message: "Profile updated successfully",
+		Success: true,
+	}
+
+	if err := idempotencyStore.Save(ctx, request.IdempotencyKey, request.DID, payloadHash, response); err != nil {
+		logrus.WithError(err).Warn("Failed to persist idempotency record")
+	}
+
+	return response, nil
+}
+
+// publishCommitEvent notifies downstream subscribers of a successful
+// profile update. previousProfile is the user's profile as it stood before
+// this update (nil if the user had none), used to populate PrevCID so
+// subscribers can detect out-of-order or duplicate deliveries. Publish
+// failures are logged, not surfaced, so a struggling event bus never fails
+// the user-facing response; delivery is expected to be retried via the
+// topic's configured DLQ.
+func publishCommitEvent(ctx context.Context, newPublisher func() (events.Publisher, error), did, rkey string, profile models.UserProfile, previousProfile *models.UserProfile) {
+	publisher, err := newPublisher()
 	if err != nil {
-		logrus.WithError(err).Error("Failed to send profile update request to AT Protocol")
-		return "", err
+		logrus.WithError(err).Warn("Failed to initialize commit event publisher")
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		logrus.WithFields(logrus.Fields{
-			"status": resp.Status,
-			"repo":   repo,
-		}).Error("Profile update failed in AT Protocol")
-		return "", fmt.Errorf("failed to update profile: %s", resp.Status)
+	var prevCID string
+	if previousProfile != nil {
+		prevCID = events.CommitCID(*previousProfile)
+	}
+
+	event := events.CommitEvent{
+		DID:        did,
+		Collection: "social.shareframe.profile",
+		Rkey:       rkey,
+		CID:        events.CommitCID(profile),
+		PrevCID:    prevCID,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
 	}
 
-	logrus.Info("Profile successfully updated in AT Protocol")
-	return "Profile successfully updated", nil
+	if err := publisher.Publish(ctx, event); err != nil {
+		logrus.WithError(err).WithField("did", did).Error("Failed to publish commit event")
+	}
 }
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ShareFrame/update-profile-service/atproto"
+	"github.com/ShareFrame/update-profile-service/dynamodb"
+	"github.com/ShareFrame/update-profile-service/events"
+	"github.com/ShareFrame/update-profile-service/idempotency"
+	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func strPtr(s string) *string { return &s }
+
+type mockDynamoDBService struct {
+	mock.Mock
+}
+
+func (m *mockDynamoDBService) UpdateUserInDynamoDB(ctx context.Context, userID string, profile models.UserProfile) (*models.UserProfile, error) {
+	args := m.Called(ctx, userID, profile)
+	out, _ := args.Get(0).(*models.UserProfile)
+	return out, args.Error(1)
+}
+
+func (m *mockDynamoDBService) GetUserEmail(ctx context.Context, userID string) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+type mockAtprotoClient struct {
+	mock.Mock
+}
+
+func (m *mockAtprotoClient) PutRecord(ctx context.Context, repo, rkey string, record interface{}, bearerToken string, validate bool) error {
+	args := m.Called(ctx, repo, rkey, record, bearerToken, validate)
+	return args.Error(0)
+}
+
+type mockIdempotencyStore struct {
+	mock.Mock
+}
+
+func (m *mockIdempotencyStore) Lookup(ctx context.Context, idempotencyKey, did, payloadHash string) (*models.UpdateProfileResponse, error) {
+	args := m.Called(ctx, idempotencyKey, did, payloadHash)
+	out, _ := args.Get(0).(*models.UpdateProfileResponse)
+	return out, args.Error(1)
+}
+
+func (m *mockIdempotencyStore) Save(ctx context.Context, idempotencyKey, did, payloadHash string, response models.UpdateProfileResponse) error {
+	args := m.Called(ctx, idempotencyKey, did, payloadHash, response)
+	return args.Error(0)
+}
+
+type mockPublisher struct {
+	mock.Mock
+}
+
+func (m *mockPublisher) Publish(ctx context.Context, event events.CommitEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+// validProfile is a profile that passes validateProfile, so tests can focus
+// on the behavior under exercise rather than validation.
+func validProfile() models.UserProfile {
+	return models.UserProfile{
+		NSID:      "social.shareframe.profile",
+		UpdatedAt: "2026-01-01T00:00:00Z",
+	}
+}
+
+func TestHandleRequest_Unauthorized(t *testing.T) {
+	dynamoSvc := new(mockDynamoDBService)
+	deps := handlerDeps{
+		newDynamoClient:     func() (dynamodb.DynamoDBService, error) { return dynamoSvc, nil },
+		newIdempotencyStore: func() (idempotency.Store, error) { return nil, nil },
+		newAtprotoClient:    func() (atproto.Client, error) { return nil, nil },
+		newPublisher:        func() (events.Publisher, error) { return nil, nil },
+		verifyJWT: func(ctx context.Context, did, authToken string) error {
+			return assert.AnError
+		},
+	}
+
+	resp, err := handleRequest(context.Background(), models.RequestPayload{DID: "did:plc:abc", Profile: validProfile()}, deps)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.UpdateProfileResponse{Message: "Unauthorized", Success: false}, resp)
+	dynamoSvc.AssertNotCalled(t, "UpdateUserInDynamoDB", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleRequest_StaleWrite(t *testing.T) {
+	dynamoSvc := new(mockDynamoDBService)
+	idempotencyStore := new(mockIdempotencyStore)
+	atprotoClient := new(mockAtprotoClient)
+
+	idempotencyStore.On("Lookup", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return((*models.UpdateProfileResponse)(nil), nil)
+	atprotoClient.On("PutRecord", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dynamoSvc.On("UpdateUserInDynamoDB", mock.Anything, mock.Anything, mock.Anything).Return((*models.UserProfile)(nil), dynamodb.ErrStaleWrite)
+
+	deps := handlerDeps{
+		newDynamoClient:     func() (dynamodb.DynamoDBService, error) { return dynamoSvc, nil },
+		newIdempotencyStore: func() (idempotency.Store, error) { return idempotencyStore, nil },
+		newAtprotoClient:    func() (atproto.Client, error) { return atprotoClient, nil },
+		newPublisher:        func() (events.Publisher, error) { return nil, nil },
+		verifyJWT:           func(ctx context.Context, did, authToken string) error { return nil },
+	}
+
+	resp, err := handleRequest(context.Background(), models.RequestPayload{DID: "did:plc:abc", Profile: validProfile()}, deps)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.UpdateProfileResponse{Message: "Conflict: profile was updated concurrently", Success: false}, resp)
+	idempotencyStore.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleRequest_Success(t *testing.T) {
+	dynamoSvc := new(mockDynamoDBService)
+	idempotencyStore := new(mockIdempotencyStore)
+	atprotoClient := new(mockAtprotoClient)
+	publisher := new(mockPublisher)
+
+	idempotencyStore.On("Lookup", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return((*models.UpdateProfileResponse)(nil), nil)
+	atprotoClient.On("PutRecord", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dynamoSvc.On("UpdateUserInDynamoDB", mock.Anything, mock.Anything, mock.Anything).Return((*models.UserProfile)(nil), nil)
+	idempotencyStore.On("Save", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	deps := handlerDeps{
+		newDynamoClient:     func() (dynamodb.DynamoDBService, error) { return dynamoSvc, nil },
+		newIdempotencyStore: func() (idempotency.Store, error) { return idempotencyStore, nil },
+		newAtprotoClient:    func() (atproto.Client, error) { return atprotoClient, nil },
+		newPublisher:        func() (events.Publisher, error) { return publisher, nil },
+		verifyJWT:           func(ctx context.Context, did, authToken string) error { return nil },
+	}
+
+	resp, err := handleRequest(context.Background(), models.RequestPayload{DID: "did:plc:abc", Profile: validProfile()}, deps)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.UpdateProfileResponse{Message: "Profile updated successfully", Success: true}, resp)
+	publisher.AssertExpectations(t)
+}
+
+func TestValidateProfile(t *testing.T) {
+	testCases := []struct {
+		name      string
+		profile   models.UserProfile
+		expectErr bool
+	}{
+		{
+			name: "valid profile",
+			profile: models.UserProfile{
+				NSID:      "social.shareframe.profile",
+				UpdatedAt: "2026-01-01T00:00:00Z",
+			},
+		},
+		{
+			name: "invalid NSID",
+			profile: models.UserProfile{
+				NSID:      "com.example.other",
+				UpdatedAt: "2026-01-01T00:00:00Z",
+			},
+			expectErr: true,
+		},
+		{
+			name: "missing updatedAt",
+			profile: models.UserProfile{
+				NSID: "social.shareframe.profile",
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid updatedAt format",
+			profile: models.UserProfile{
+				NSID:      "social.shareframe.profile",
+				UpdatedAt: "not-a-date",
+			},
+			expectErr: true,
+		},
+		{
+			name: "bio too long",
+			profile: models.UserProfile{
+				NSID:      "social.shareframe.profile",
+				UpdatedAt: "2026-01-01T00:00:00Z",
+				Bio:       strPtr(string(make([]byte, 257))),
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid hex color",
+			profile: models.UserProfile{
+				NSID:         "social.shareframe.profile",
+				UpdatedAt:    "2026-01-01T00:00:00Z",
+				PrimaryColor: strPtr("not-a-color"),
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid theme",
+			profile: models.UserProfile{
+				NSID:      "social.shareframe.profile",
+				UpdatedAt: "2026-01-01T00:00:00Z",
+				Theme:     strPtr("neon"),
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateProfile(tc.profile)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIsValidHexColor(t *testing.T) {
+	assert.True(t, isValidHexColor("#fff"))
+	assert.True(t, isValidHexColor("#FFAABB"))
+	assert.False(t, isValidHexColor("fff"))
+	assert.False(t, isValidHexColor("#ggg"))
+}
+
+func TestIsValidURL(t *testing.T) {
+	assert.True(t, isValidURL("https://example.com/pic.png"))
+	assert.True(t, isValidURL("example.com/pic.png"))
+	assert.False(t, isValidURL("not a url"))
+}
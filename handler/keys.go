@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/mr-tron/base58"
+)
+
+// multicodec prefixes for the key types AT Protocol uses today.
+const (
+	multicodecP256      = 0x1200
+	multicodecSecp256k1 = 0xe7
+)
+
+// parseMultibasePublicKey decodes a did:key-style multibase/multicodec public
+// key embedded in a verificationMethod into a key usable by jwt.Parse's
+// keyfunc.
+func parseMultibasePublicKey(vm VerificationMethod) (interface{}, error) {
+	if !strings.HasPrefix(vm.PublicKeyMultibase, "z") {
+		return nil, fmt.Errorf("unsupported multibase prefix for verification method %s", vm.ID)
+	}
+
+	decoded, err := base58.Decode(vm.PublicKeyMultibase[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to base58-decode public key for %s: %w", vm.ID, err)
+	}
+
+	codec, n := decodeVarint(decoded)
+	keyBytes := decoded[n:]
+
+	switch codec {
+	case multicodecP256:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), keyBytes)
+		if x == nil {
+			return nil, fmt.Errorf("invalid P-256 compressed public key for %s", vm.ID)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case multicodecSecp256k1:
+		pub, err := secp256k1.ParsePubKey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secp256k1 public key for %s: %w", vm.ID, err)
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("unsupported key codec 0x%x for %s", codec, vm.ID)
+	}
+}
+
+// decodeVarint reads an unsigned LEB128 varint, returning the value and the
+// number of bytes consumed.
+func decodeVarint(data []byte) (uint64, int) {
+	var value uint64
+	var shift uint
+	for i, b := range data {
+		value |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return value, i + 1
+		}
+		shift += 7
+	}
+	return value, len(data)
+}
@@ -0,0 +1,164 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	tableName = "ProfileUpdateIdempotency"
+	recordTTL = 24 * time.Hour
+)
+
+// ErrPayloadMismatch is returned when an idempotency key is reused with a
+// profile payload that differs from the one it was first associated with.
+var ErrPayloadMismatch = errors.New("idempotency key already used with a different request payload")
+
+// ErrAlreadyClaimed is returned when two concurrent callers race to Save the
+// same idempotency key; the loser should treat this as "someone else owns
+// this key" rather than overwrite the winner's response.
+var ErrAlreadyClaimed = errors.New("idempotency key already claimed by a concurrent request")
+
+// Record is the item stored per idempotency key, keyed by DID so the same
+// client-supplied key can't be replayed across different callers.
+type Record struct {
+	IdempotencyKey string                       `dynamodbav:"IdempotencyKey"`
+	DID            string                       `dynamodbav:"DID"`
+	ResponseHash   string                       `dynamodbav:"ResponseHash"`
+	Response       models.UpdateProfileResponse `dynamodbav:"Response"`
+	TTL            int64                        `dynamodbav:"TTL"`
+}
+
+// DynamoDBAPI is the subset of the DynamoDB client the idempotency store
+// depends on, mirroring the dynamodb package's pattern for mockability.
+type DynamoDBAPI interface {
+	PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+// Store resolves and records idempotent profile update responses.
+type Store interface {
+	Lookup(ctx context.Context, idempotencyKey, did string, payloadHash string) (*models.UpdateProfileResponse, error)
+	Save(ctx context.Context, idempotencyKey, did, payloadHash string, response models.UpdateProfileResponse) error
+}
+
+var _ Store = (*DynamoStore)(nil)
+
+type DynamoStore struct {
+	Client DynamoDBAPI
+}
+
+func NewDynamoStore() (*DynamoStore, error) {
+	awsCfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
+	}
+
+	return &DynamoStore{Client: dynamodb.NewFromConfig(awsCfg)}, nil
+}
+
+// Lookup returns the cached response for idempotencyKey+did if one exists and
+// was stored for the same payloadHash. It returns ErrPayloadMismatch if the
+// key was previously used with a different payload, and (nil, nil) on a
+// cache miss.
+func (s *DynamoStore) Lookup(ctx context.Context, idempotencyKey, did, payloadHash string) (*models.UpdateProfileResponse, error) {
+	if idempotencyKey == "" {
+		return nil, nil
+	}
+
+	out, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]types.AttributeValue{
+			"IdempotencyKey": &types.AttributeValueMemberS{Value: idempotencyKey},
+			"DID":            &types.AttributeValueMemberS{Value: did},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency record: %w", err)
+	}
+
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+
+	var record Record
+	if err := attributevalue.UnmarshalMap(out.Item, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+
+	if record.ResponseHash != payloadHash {
+		return nil, ErrPayloadMismatch
+	}
+
+	return &record.Response, nil
+}
+
+// Save stores the response for idempotencyKey+did with a TTL so Lambda
+// retries within the window short-circuit instead of re-applying the update.
+// The write is conditioned on the key not already existing, so two concurrent
+// callers racing on the same idempotency key can't clobber each other's
+// response; the loser gets ErrAlreadyClaimed.
+func (s *DynamoStore) Save(ctx context.Context, idempotencyKey, did, payloadHash string, response models.UpdateProfileResponse) error {
+	if idempotencyKey == "" {
+		return nil
+	}
+
+	item, err := attributevalue.MarshalMap(Record{
+		IdempotencyKey: idempotencyKey,
+		DID:            did,
+		ResponseHash:   payloadHash,
+		Response:       response,
+		TTL:            time.Now().Add(recordTTL).Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	if _, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(IdempotencyKey)"),
+	}); err != nil {
+		var condFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &condFailed) {
+			return ErrAlreadyClaimed
+		}
+		return fmt.Errorf("failed to persist idempotency record: %w", err)
+	}
+
+	return nil
+}
+
+// HashPayload produces the deterministic ResponseHash used to detect when an
+// idempotency key is replayed with a different profile payload.
+func HashPayload(did string, profile models.UserProfile) (string, error) {
+	body, err := json.Marshal(profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal profile for idempotency hash: %w", err)
+	}
+
+	sum := sha256.Sum256(append([]byte(did+":"), body...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// DeriveRKey produces a deterministic AT Protocol record key from the DID
+// alone, so every update for the same user resolves to the same repo
+// record regardless of whether (or how) the caller uses IdempotencyKey.
+// IdempotencyKey is scoped to response caching only (see Lookup/Save); a
+// caller minting a fresh key per request must not also get a fresh record.
+func DeriveRKey(did string) string {
+	sum := sha256.Sum256([]byte(did))
+	return hex.EncodeToString(sum[:16])
+}
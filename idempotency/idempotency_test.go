@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ShareFrame/update-profile-service/models"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDynamoDBAPI struct {
+	mock.Mock
+}
+
+func (m *MockDynamoDBAPI) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*dynamodb.PutItemOutput)
+	return out, args.Error(1)
+}
+
+func (m *MockDynamoDBAPI) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*dynamodb.GetItemOutput)
+	return out, args.Error(1)
+}
+
+func TestSave_ConditionalCheckFailed_ReturnsErrAlreadyClaimed(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return input.ConditionExpression != nil && *input.ConditionExpression == "attribute_not_exists(IdempotencyKey)"
+	})).Return((*dynamodb.PutItemOutput)(nil), &types.ConditionalCheckFailedException{})
+
+	err := store.Save(context.Background(), "key-1", "did:plc:abc", "hash", models.UpdateProfileResponse{Success: true})
+
+	assert.True(t, errors.Is(err, ErrAlreadyClaimed))
+	mockDynamoDB.AssertExpectations(t)
+}
+
+func TestSave_OtherError_IsWrapped(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("PutItem", mock.Anything, mock.Anything).
+		Return((*dynamodb.PutItemOutput)(nil), errors.New("throttled"))
+
+	err := store.Save(context.Background(), "key-1", "did:plc:abc", "hash", models.UpdateProfileResponse{Success: true})
+
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrAlreadyClaimed))
+}
+
+func TestSave_EmptyKey_NoOp(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	err := store.Save(context.Background(), "", "did:plc:abc", "hash", models.UpdateProfileResponse{Success: true})
+
+	assert.NoError(t, err)
+	mockDynamoDB.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything)
+}
+
+func TestLookup_PayloadMismatch(t *testing.T) {
+	mockDynamoDB := new(MockDynamoDBAPI)
+	store := &DynamoStore{Client: mockDynamoDB}
+
+	mockDynamoDB.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{
+		Item: map[string]types.AttributeValue{
+			"IdempotencyKey": &types.AttributeValueMemberS{Value: "key-1"},
+			"DID":            &types.AttributeValueMemberS{Value: "did:plc:abc"},
+			"ResponseHash":   &types.AttributeValueMemberS{Value: "different-hash"},
+			"Response":       &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}},
+			"TTL":            &types.AttributeValueMemberN{Value: "0"},
+		},
+	}, nil)
+
+	_, err := store.Lookup(context.Background(), "key-1", "did:plc:abc", "hash")
+
+	assert.True(t, errors.Is(err, ErrPayloadMismatch))
+}
+
+func TestDeriveRKey_StableForSameDID(t *testing.T) {
+	assert.Equal(t, DeriveRKey("did:plc:abc"), DeriveRKey("did:plc:abc"))
+}
+
+func TestDeriveRKey_DiffersAcrossDIDs(t *testing.T) {
+	assert.NotEqual(t, DeriveRKey("did:plc:abc"), DeriveRKey("did:plc:xyz"))
+}
@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+const fromAddress = "no-reply@shareframe.social"
+
+// Sender delivers transactional email. It exists so handlers can be tested
+// without talking to SES.
+type Sender interface {
+	SendConfirmationEmail(ctx context.Context, to, confirmationURL string) error
+}
+
+var _ Sender = (*SESSender)(nil)
+
+// SESAPI is the subset of the SES v2 client this package depends on.
+type SESAPI interface {
+	SendEmail(ctx context.Context, input *sesv2.SendEmailInput, opts ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+}
+
+type SESSender struct {
+	Client SESAPI
+}
+
+func NewSESSender() (*SESSender, error) {
+	awsCfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
+	}
+
+	return &SESSender{Client: sesv2.NewFromConfig(awsCfg)}, nil
+}
+
+func (s *SESSender) SendConfirmationEmail(ctx context.Context, to, confirmationURL string) error {
+	subject := "Confirm your ShareFrame profile change"
+	body := fmt.Sprintf("We received a request to update your ShareFrame profile. "+
+		"If this was you, confirm the change within 15 minutes:\n\n%s\n\n"+
+		"If you didn't request this, you can safely ignore this email.", confirmationURL)
+
+	_, err := s.Client.SendEmail(ctx, &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(fromAddress),
+		Destination: &types.Destination{
+			ToAddresses: []string{to},
+		},
+		Content: &types.EmailContent{
+			Simple: &types.Message{
+				Subject: &types.Content{Data: aws.String(subject)},
+				Body: &types.Body{
+					Text: &types.Content{Data: aws.String(body)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send confirmation email to %s: %w", to, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSESAPI struct {
+	mock.Mock
+}
+
+func (m *MockSESAPI) SendEmail(ctx context.Context, input *sesv2.SendEmailInput, opts ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+	args := m.Called(ctx, input)
+	out, _ := args.Get(0).(*sesv2.SendEmailOutput)
+	return out, args.Error(1)
+}
+
+func TestSendConfirmationEmail(t *testing.T) {
+	mockSES := new(MockSESAPI)
+	sender := &SESSender{Client: mockSES}
+
+	mockSES.On("SendEmail", mock.Anything, mock.MatchedBy(func(input *sesv2.SendEmailInput) bool {
+		return *input.FromEmailAddress == fromAddress &&
+			len(input.Destination.ToAddresses) == 1 &&
+			input.Destination.ToAddresses[0] == "user@example.com"
+	})).Return(&sesv2.SendEmailOutput{}, nil)
+
+	err := sender.SendConfirmationEmail(context.Background(), "user@example.com", "https://shareframe.social/profile/confirm?token=abc")
+
+	assert.NoError(t, err)
+	mockSES.AssertExpectations(t)
+}
+
+func TestSendConfirmationEmail_Error(t *testing.T) {
+	mockSES := new(MockSESAPI)
+	sender := &SESSender{Client: mockSES}
+
+	mockSES.On("SendEmail", mock.Anything, mock.Anything).Return(nil, errors.New("SES is down"))
+
+	err := sender.SendConfirmationEmail(context.Background(), "user@example.com", "https://shareframe.social/profile/confirm?token=abc")
+
+	assert.Error(t, err)
+}
@@ -1,10 +1,31 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/ShareFrame/update-profile-service/handler"
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
+// This binary backs three separate Lambda functions (profile updates,
+// confirmation requests, and confirmation links), selected at deploy time by
+// the HANDLER_NAME environment variable on each function's configuration.
+const (
+	handlerUpdateProfile        = "update-profile"
+	handlerRequestConfirmation  = "request-profile-change-confirmation"
+	handlerConfirmProfileChange = "confirm-profile-change"
+)
+
 func main() {
-	lambda.Start(handler.HandleRequest)
+	switch name := os.Getenv("HANDLER_NAME"); name {
+	case handlerUpdateProfile, "":
+		lambda.Start(handler.HandleRequest)
+	case handlerRequestConfirmation:
+		lambda.Start(handler.RequestProfileChangeConfirmation)
+	case handlerConfirmProfileChange:
+		lambda.Start(handler.ConfirmProfileChange)
+	default:
+		panic(fmt.Sprintf("unknown HANDLER_NAME %q", name))
+	}
 }
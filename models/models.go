@@ -1,24 +1,53 @@
 package models
 
+// UserProfile is the AT Protocol record. UpdatedAt doubles as the
+// optimistic-concurrency token: clients send back the UpdatedAt they last
+// observed, and the update is rejected if the stored record has moved on.
+//
+// Bio, ProfilePicture, ProfileBanner, Theme, PrimaryColor and SecondaryColor
+// are pointers so a patch can distinguish "leave unset" (nil) from "clear
+// this field" (pointer to "").
 type UserProfile struct {
-	NSID           string `json:"nsid"`
-	DisplayName    string `json:"displayName"`
-	Bio            string `json:"bio,omitempty"`
-	ProfilePicture string `json:"profilePicture,omitempty"`
-	ProfileBanner  string `json:"profileBanner,omitempty"`
-	Theme          string `json:"theme,omitempty"`
-	PrimaryColor   string `json:"primaryColor,omitempty"`
-	SecondaryColor string `json:"secondaryColor,omitempty"`
-	UpdatedAt      string `json:"updatedAt"`
+	NSID           string  `json:"nsid"`
+	DisplayName    string  `json:"displayName"`
+	Bio            *string `json:"bio,omitempty"`
+	ProfilePicture *string `json:"profilePicture,omitempty"`
+	ProfileBanner  *string `json:"profileBanner,omitempty"`
+	Theme          *string `json:"theme,omitempty"`
+	PrimaryColor   *string `json:"primaryColor,omitempty"`
+	SecondaryColor *string `json:"secondaryColor,omitempty"`
+	UpdatedAt      string  `json:"updatedAt"`
 }
 
 type RequestPayload struct {
+	DID            string      `json:"did"`
+	Profile        UserProfile `json:"profile"`
+	AuthToken      string      `json:"authToken"`
+	IdempotencyKey string      `json:"idempotencyKey,omitempty"`
+}
+
+type UpdateProfileResponse struct {
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+}
+
+// ProfileChangeConfirmationRequest requests out-of-band confirmation of a
+// profile-sensitive change before it is applied. The confirmation email is
+// sent to the account's email of record, looked up server-side by DID rather
+// than taken from the request, so the caller can't redirect it elsewhere.
+type ProfileChangeConfirmationRequest struct {
 	DID       string      `json:"did"`
 	Profile   UserProfile `json:"profile"`
 	AuthToken string      `json:"authToken"`
 }
 
-type UpdateProfileResponse struct {
+type ProfileChangeConfirmationResponse struct {
 	Message string `json:"message"`
 	Success bool   `json:"success"`
 }
+
+// ConfirmProfileChangeRequest carries the single-use token from the
+// confirmation email back to the service.
+type ConfirmProfileChangeRequest struct {
+	Token string `json:"token"`
+}